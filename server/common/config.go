@@ -5,6 +5,7 @@ import (
 	"net"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/BurntSushi/toml"
@@ -55,6 +56,35 @@ type Configuration struct {
 	OvhAPIKey            string   `json:"-"`
 	OvhAPISecret         string   `json:"-"`
 
+	OIDCAuthentication bool     `json:"oidcAuthentication"`
+	OIDCIssuerURL      string   `json:"-"`
+	OIDCClientID       string   `json:"-"`
+	OIDCClientSecret   string   `json:"-"`
+	OIDCScopes         []string `json:"-"`
+	OIDCRedirectURL    string   `json:"-"`
+	OIDCUsernameClaim  string   `json:"-"`
+	OIDCGroupsClaim    string   `json:"-"`
+	OIDCAllowedGroups  []string `json:"-"`
+
+	SessionMaxLifetime int    `json:"-"`
+	SessionIdleTimeout int    `json:"-"`
+	SessionSecret      string `json:"-"`
+
+	AdminAPIKey string `json:"-"`
+
+	MetricsEnabled       bool   `json:"-"`
+	MetricsListenAddress string `json:"-"`
+	MetricsPath          string `json:"-"`
+	HealthPath           string `json:"-"`
+	HealthMinFreeBytes   uint64 `json:"-"`
+
+	LogFormat           string   `json:"-"`
+	LogFields           []string `json:"-"`
+	LogSampling         float64  `json:"-"`
+	RemoteLogURL        string   `json:"-"`
+	RemoteLogToken      string   `json:"-"`
+	RemoteLogBufferPath string   `json:"-"`
+
 	MetadataBackendConfig map[string]interface{} `json:"-"`
 
 	DataBackend       string                 `json:"-"`
@@ -63,6 +93,11 @@ type Configuration struct {
 	downloadDomainURL *url.URL
 	uploadWhitelist   []*net.IPNet
 	clean             bool
+
+	oidcProvider *OIDCProvider
+
+	configFilePath string
+	current        atomic.Pointer[Configuration]
 }
 
 // NewConfiguration creates a new configuration
@@ -87,6 +122,21 @@ func NewConfiguration() (config *Configuration) {
 
 	config.OvhAPIEndpoint = "https://eu.api.ovh.com/1.0"
 
+	config.OIDCScopes = []string{"openid", "profile", "email"}
+	config.OIDCUsernameClaim = "email"
+
+	config.SessionMaxLifetime = 2592000 // 30 days
+	config.SessionIdleTimeout = 86400   // 1 day
+
+	config.MetricsListenAddress = "0.0.0.0:2112"
+	config.MetricsPath = "/metrics"
+	config.HealthPath = "/health"
+	config.HealthMinFreeBytes = 100 * 1000 * 1000 // 100MB
+
+	config.LogFormat = "text"
+	config.LogFields = []string{"request_id", "method", "path", "status", "latency"}
+	config.LogSampling = 1.0
+
 	config.DataBackend = "file"
 
 	config.clean = true
@@ -98,6 +148,7 @@ func NewConfiguration() (config *Configuration) {
 // override default params
 func LoadConfiguration(file string) (config *Configuration, err error) {
 	config = NewConfiguration()
+	config.configFilePath = file
 
 	if _, err := toml.DecodeFile(file, config); err != nil {
 		return nil, fmt.Errorf("unable to load config file %s : %s", file, err)
@@ -108,6 +159,8 @@ func LoadConfiguration(file string) (config *Configuration, err error) {
 		return nil, err
 	}
 
+	config.current.Store(config)
+
 	return config, nil
 }
 
@@ -146,10 +199,25 @@ func (config *Configuration) Initialize() (err error) {
 		config.OvhAuthentication = false
 	}
 
+	if config.OIDCIssuerURL != "" && config.OIDCClientID != "" && config.OIDCClientSecret != "" {
+		config.OIDCAuthentication = true
+	} else {
+		config.OIDCAuthentication = false
+	}
+
 	if !config.Authentication {
 		config.NoAnonymousUploads = false
 		config.GoogleAuthentication = false
 		config.OvhAuthentication = false
+		config.OIDCAuthentication = false
+	}
+
+	if config.OIDCAuthentication {
+		provider, err := NewOIDCProvider(config)
+		if err != nil {
+			return fmt.Errorf("unable to initialize OIDC provider : %s", err)
+		}
+		config.oidcProvider = provider
 	}
 
 	if config.DownloadDomain != "" {
@@ -160,6 +228,15 @@ func (config *Configuration) Initialize() (err error) {
 		}
 	}
 
+	return config.validate()
+}
+
+// validate checks the invariants that must hold across the whole
+// configuration once every field has been parsed. It is called by
+// Initialize() at startup and by Reload() before a new configuration
+// is swapped in, so a bad TOML file on SIGHUP can never take down a
+// running server.
+func (config *Configuration) validate() (err error) {
 	if config.DefaultTTL > config.MaxTTL {
 		return fmt.Errorf("DefaultTTL should not be more than MaxTTL")
 	}
@@ -167,7 +244,10 @@ func (config *Configuration) Initialize() (err error) {
 	return nil
 }
 
-// NewLogger returns a new logger instance
+// NewLogger returns a new logger instance. The returned logger is used
+// for Plik's own operational logs ; per-request structured logging is
+// handled separately by the logging package, driven by LogFormat,
+// LogFields, LogSampling and the optional RemoteLogURL shipper.
 func (config *Configuration) NewLogger() (log *logger.Logger) {
 	level := "INFO"
 	if config.Debug {
@@ -214,6 +294,54 @@ func (config *Configuration) IsWhitelisted(ip net.IP) bool {
 	return false
 }
 
+// GetOIDCProvider return the discovered OIDC provider, or nil if OIDC
+// authentication is not enabled
+func (config *Configuration) GetOIDCProvider() *OIDCProvider {
+	return config.oidcProvider
+}
+
+// Current returns the live configuration snapshot. Handlers should
+// read settings through Current() rather than a captured *Configuration
+// so a reload takes effect immediately.
+func (config *Configuration) Current() *Configuration {
+	if current := config.current.Load(); current != nil {
+		return current
+	}
+	return config
+}
+
+// Reload re-parses the TOML file at path and atomically swaps the
+// mutable fields into the snapshot returned by Current(). Immutable
+// fields are rejected with an error instead of being silently ignored.
+func (config *Configuration) Reload(path string) (err error) {
+	current := config.Current()
+
+	next := NewConfiguration()
+	next.configFilePath = path
+
+	if _, err = toml.DecodeFile(path, next); err != nil {
+		return fmt.Errorf("unable to load config file %s : %s", path, err)
+	}
+
+	if err = next.Initialize(); err != nil {
+		return fmt.Errorf("invalid configuration : %s", err)
+	}
+
+	if next.ListenAddress != current.ListenAddress || next.ListenPort != current.ListenPort {
+		return fmt.Errorf("unable to reload configuration : ListenAddress and ListenPort cannot be changed at runtime")
+	}
+	if next.DataBackend != current.DataBackend {
+		return fmt.Errorf("unable to reload configuration : DataBackend cannot be changed at runtime")
+	}
+	if next.SslEnabled != current.SslEnabled {
+		return fmt.Errorf("unable to reload configuration : SslEnabled cannot be changed at runtime")
+	}
+
+	config.current.Store(next)
+
+	return nil
+}
+
 // GetServerURL is a helper to get the server HTTP URL
 func (config *Configuration) GetServerURL() *url.URL {
 	URL := &url.URL{}
@@ -243,6 +371,23 @@ func (config *Configuration) String() string {
 		str += fmt.Sprintf("Download domain : %s\n", config.DownloadDomain)
 	}
 
+	if config.MetricsEnabled {
+		str += fmt.Sprintf("Metrics : enabled on %s%s\n", config.MetricsListenAddress, config.MetricsPath)
+	}
+
+	str += fmt.Sprintf("Log format : %s\n", config.LogFormat)
+	if config.RemoteLogURL != "" {
+		str += fmt.Sprintf("Remote log shipping : enabled to %s\n", config.RemoteLogURL)
+	}
+
+	str += fmt.Sprintf("Data backend : %s\n", config.DataBackend)
+	if config.DataBackend == "s3" {
+		// DataBackendConfig keys : Endpoint, Region, Bucket, AccessKey,
+		// SecretKey, UseSSL, PathStyle, SSECustomerKey, PartSize, UserAgent
+		str += fmt.Sprintf("S3 endpoint : %v\n", config.DataBackendConfig["Endpoint"])
+		str += fmt.Sprintf("S3 bucket : %v\n", config.DataBackendConfig["Bucket"])
+	}
+
 	str += fmt.Sprintf("Maximum file size : %s\n", humanize.Bytes(uint64(config.MaxFileSize)))
 	str += fmt.Sprintf("Maximum files per upload : %d\n", config.MaxFilePerUpload)
 
@@ -299,6 +444,13 @@ func (config *Configuration) String() string {
 		} else {
 			str += fmt.Sprintf("OVH authentication : disabled\n")
 		}
+
+		if config.OIDCAuthentication {
+			str += fmt.Sprintf("OIDC authentication : enabled\n")
+			str += fmt.Sprintf("OIDC issuer : %s\n", config.OIDCIssuerURL)
+		} else {
+			str += fmt.Sprintf("OIDC authentication : disabled\n")
+		}
 	} else {
 		str += fmt.Sprintf("Authentication : disabled\n")
 	}
@@ -0,0 +1,58 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestConfigFile(t *testing.T, dir string, body string) string {
+	path := filepath.Join(dir, "plik.toml")
+	if err := os.WriteFile(path, []byte(body), 0600); err != nil {
+		t.Fatalf("unable to write test config : %s", err)
+	}
+	return path
+}
+
+func TestReloadUpdatesCurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestConfigFile(t, dir, "MaxFileSize = 1000\n")
+
+	config, err := LoadConfiguration(path)
+	if err != nil {
+		t.Fatalf("unable to load configuration : %s", err)
+	}
+	if config.Current().MaxFileSize != 1000 {
+		t.Fatalf("expected initial MaxFileSize 1000, got %d", config.Current().MaxFileSize)
+	}
+
+	writeTestConfigFile(t, dir, "MaxFileSize = 2000\n")
+	if err := config.Reload(path); err != nil {
+		t.Fatalf("unable to reload configuration : %s", err)
+	}
+
+	if config.Current().MaxFileSize != 2000 {
+		t.Fatalf("expected Current() to reflect the reloaded MaxFileSize, got %d", config.Current().MaxFileSize)
+	}
+	if config.MaxFileSize != 1000 {
+		t.Fatalf("expected the original struct to stay untouched by Reload, got %d", config.MaxFileSize)
+	}
+}
+
+func TestReloadRejectsImmutableFields(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestConfigFile(t, dir, "ListenPort = 8080\n")
+
+	config, err := LoadConfiguration(path)
+	if err != nil {
+		t.Fatalf("unable to load configuration : %s", err)
+	}
+
+	writeTestConfigFile(t, dir, "ListenPort = 9090\n")
+	if err := config.Reload(path); err == nil {
+		t.Fatalf("expected Reload to reject a changed ListenPort")
+	}
+	if config.Current().ListenPort != 8080 {
+		t.Fatalf("expected Current() to still report the original ListenPort after a rejected reload")
+	}
+}
@@ -0,0 +1,191 @@
+package common
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// oauthHTTPClient performs the refresh-token exchange. Token() holds
+// source.mu while it refreshes, so a provider that never responds
+// would otherwise serialize every request for that user behind a
+// refresh call that never returns.
+var oauthHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// OAuthTokenSource transparently refreshes a Google/OVH access token
+// using its stored refresh token before each authenticated request. A
+// single instance is shared by all requests for a given user session,
+// so the mutex prevents concurrent callers from stampeding the token
+// endpoint. SessionMaxLifetime and SessionIdleTimeout bound how long
+// the underlying session may be kept alive by refreshing.
+type OAuthTokenSource struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+
+	maxLifetime time.Duration
+	idleTimeout time.Duration
+
+	mu           sync.Mutex
+	RefreshToken string
+	accessToken  string
+	expiry       time.Time
+	createdAt    time.Time
+	lastUsed     time.Time
+}
+
+// NewOAuthTokenSource creates a token source seeded with the refresh
+// token obtained at login time, bounded by config.SessionMaxLifetime
+// and config.SessionIdleTimeout.
+func NewOAuthTokenSource(config *Configuration, tokenURL string, clientID string, clientSecret string, refreshToken string) *OAuthTokenSource {
+	now := time.Now()
+	return &OAuthTokenSource{
+		TokenURL:     tokenURL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RefreshToken: refreshToken,
+		maxLifetime:  time.Duration(config.SessionMaxLifetime) * time.Second,
+		idleTimeout:  time.Duration(config.SessionIdleTimeout) * time.Second,
+		createdAt:    now,
+		lastUsed:     now,
+	}
+}
+
+// Token returns a valid access token, refreshing it first if it has
+// expired or is about to. It fails once the session has outlived
+// SessionMaxLifetime or has been idle past SessionIdleTimeout.
+func (source *OAuthTokenSource) Token() (accessToken string, err error) {
+	source.mu.Lock()
+	defer source.mu.Unlock()
+
+	now := time.Now()
+	if source.maxLifetime > 0 && now.After(source.createdAt.Add(source.maxLifetime)) {
+		return "", fmt.Errorf("session exceeded its maximum lifetime")
+	}
+	if source.idleTimeout > 0 && now.After(source.lastUsed.Add(source.idleTimeout)) {
+		return "", fmt.Errorf("session has been idle for too long")
+	}
+
+	if source.accessToken == "" || !now.Add(30*time.Second).Before(source.expiry) {
+		if err = source.refresh(); err != nil {
+			return "", err
+		}
+	}
+
+	source.lastUsed = now
+	return source.accessToken, nil
+}
+
+// refresh exchanges the stored refresh token for a new access token.
+// Callers must hold source.mu.
+func (source *OAuthTokenSource) refresh() (err error) {
+	values := url.Values{}
+	values.Set("grant_type", "refresh_token")
+	values.Set("refresh_token", source.RefreshToken)
+	values.Set("client_id", source.ClientID)
+	values.Set("client_secret", source.ClientSecret)
+
+	resp, err := oauthHTTPClient.PostForm(source.TokenURL, values)
+	if err != nil {
+		return fmt.Errorf("unable to refresh OAuth token : %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to refresh OAuth token : got HTTP status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("unable to parse OAuth token response : %s", err)
+	}
+
+	source.accessToken = body.AccessToken
+	source.expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	if body.RefreshToken != "" {
+		// Some providers rotate the refresh token on every use.
+		source.RefreshToken = body.RefreshToken
+	}
+
+	return nil
+}
+
+// EncryptRefreshToken seals refreshToken with AES-GCM under a key
+// derived from secret, for storage on the Session keyed to the user's
+// session id.
+func EncryptRefreshToken(secret string, refreshToken string) (string, error) {
+	return encryptSessionSecret(secret, refreshToken)
+}
+
+// DecryptRefreshToken reverses EncryptRefreshToken, for rebuilding an
+// OAuthTokenSource from a Session's stored refresh token.
+func DecryptRefreshToken(secret string, ciphertext string) (string, error) {
+	return decryptSessionSecret(secret, ciphertext)
+}
+
+// sessionEncryptionKey derives a 32 byte AES-256 key from an
+// operator-supplied secret of any length.
+func sessionEncryptionKey(secret string) [32]byte {
+	return sha256.Sum256([]byte(secret))
+}
+
+func encryptSessionSecret(secret string, plaintext string) (string, error) {
+	key := sessionEncryptionKey(secret)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return "", fmt.Errorf("unable to build session cipher : %s", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", fmt.Errorf("unable to build session cipher : %s", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("unable to generate session nonce : %s", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptSessionSecret(secret string, ciphertext string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("unable to decode session secret : %s", err)
+	}
+
+	key := sessionEncryptionKey(secret)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return "", fmt.Errorf("unable to build session cipher : %s", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", fmt.Errorf("unable to build session cipher : %s", err)
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("malformed session secret")
+	}
+	nonce, sealed := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to decrypt session secret : %s", err)
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEncryptDecryptRefreshTokenRoundTrip(t *testing.T) {
+	encrypted, err := EncryptRefreshToken("s3cr3t", "refresh-token-value")
+	if err != nil {
+		t.Fatalf("unable to encrypt refresh token : %s", err)
+	}
+	if encrypted == "refresh-token-value" {
+		t.Fatalf("expected the refresh token to actually be encrypted")
+	}
+
+	decrypted, err := DecryptRefreshToken("s3cr3t", encrypted)
+	if err != nil {
+		t.Fatalf("unable to decrypt refresh token : %s", err)
+	}
+	if decrypted != "refresh-token-value" {
+		t.Fatalf("expected decrypted refresh token to match, got %q", decrypted)
+	}
+}
+
+func TestDecryptRefreshTokenRejectsWrongSecret(t *testing.T) {
+	encrypted, err := EncryptRefreshToken("s3cr3t", "refresh-token-value")
+	if err != nil {
+		t.Fatalf("unable to encrypt refresh token : %s", err)
+	}
+
+	if _, err := DecryptRefreshToken("wrong-secret", encrypted); err == nil {
+		t.Fatalf("expected decryption with the wrong secret to fail")
+	}
+}
+
+func TestOAuthTokenSourceRejectsExpiredSession(t *testing.T) {
+	config := NewConfiguration()
+	config.SessionMaxLifetime = 1
+
+	source := NewOAuthTokenSource(config, "https://example.com/token", "client-id", "client-secret", "refresh-token")
+	source.createdAt = time.Now().Add(-time.Hour)
+	source.lastUsed = time.Now()
+
+	if _, err := source.Token(); err == nil {
+		t.Fatalf("expected Token() to reject a session past its maximum lifetime")
+	}
+}
+
+func TestOAuthTokenSourceRejectsIdleSession(t *testing.T) {
+	config := NewConfiguration()
+	config.SessionIdleTimeout = 1
+
+	source := NewOAuthTokenSource(config, "https://example.com/token", "client-id", "client-secret", "refresh-token")
+	source.createdAt = time.Now()
+	source.lastUsed = time.Now().Add(-time.Hour)
+
+	if _, err := source.Token(); err == nil {
+		t.Fatalf("expected Token() to reject a session idle past SessionIdleTimeout")
+	}
+}
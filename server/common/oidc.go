@@ -0,0 +1,306 @@
+package common
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// oidcDiscoveryPath is appended to the configured issuer URL to fetch
+// the provider metadata document.
+const oidcDiscoveryPath = "/.well-known/openid-configuration"
+
+// oidcHTTPClient drives discovery, JWKS and code-exchange requests to
+// the configured issuer. Without a timeout, a stalled issuer would
+// block every login attempt in flight, since OIDCCallback calls these
+// synchronously while the user is waiting on the redirect.
+var oidcHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// OIDCProvider holds the metadata and cached keys discovered from a
+// generic OpenID Connect issuer, as configured by OIDCIssuerURL.
+type OIDCProvider struct {
+	IssuerURL             string
+	AuthorizationEndpoint string
+	TokenEndpoint         string
+	UserinfoEndpoint      string
+	JwksURI               string
+	RevocationEndpoint    string
+
+	jwks      *oidcJWKS
+	jwksFetch time.Time
+}
+
+// oidcJWKS is the minimal JSON Web Key Set representation needed to
+// verify ID token signatures.
+type oidcJWKS struct {
+	Keys []oidcJWK `json:"keys"`
+}
+
+// oidcJWK is a single RSA signing key as published by the provider.
+type oidcJWK struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// oidcDiscoveryDocument is the subset of the discovery document fields
+// Plik needs to drive the authorization-code flow.
+type oidcDiscoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+	RevocationEndpoint    string `json:"revocation_endpoint"`
+}
+
+// OIDCTokenResponse is the token endpoint response for the
+// authorization_code grant.
+type OIDCTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	IDToken      string `json:"id_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// NewOIDCProvider discovers the OIDC provider metadata at
+// config.OIDCIssuerURL and fetches its JWKS for ID token verification.
+func NewOIDCProvider(config *Configuration) (provider *OIDCProvider, err error) {
+	doc, err := fetchOIDCDiscoveryDocument(config.OIDCIssuerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	provider = &OIDCProvider{
+		IssuerURL:             doc.Issuer,
+		AuthorizationEndpoint: doc.AuthorizationEndpoint,
+		TokenEndpoint:         doc.TokenEndpoint,
+		UserinfoEndpoint:      doc.UserinfoEndpoint,
+		JwksURI:               doc.JwksURI,
+		RevocationEndpoint:    doc.RevocationEndpoint,
+	}
+
+	if err = provider.refreshJWKS(); err != nil {
+		return nil, err
+	}
+
+	return provider, nil
+}
+
+func fetchOIDCDiscoveryDocument(issuerURL string) (doc *oidcDiscoveryDocument, err error) {
+	resp, err := oidcHTTPClient.Get(issuerURL + oidcDiscoveryPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch OIDC discovery document : %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch OIDC discovery document : got HTTP status %d", resp.StatusCode)
+	}
+
+	doc = &oidcDiscoveryDocument{}
+	if err = json.NewDecoder(resp.Body).Decode(doc); err != nil {
+		return nil, fmt.Errorf("unable to parse OIDC discovery document : %s", err)
+	}
+
+	return doc, nil
+}
+
+// refreshJWKS fetches and caches the provider's JSON Web Key Set. It is
+// called once at startup and again whenever a token is signed with an
+// unknown key ID.
+func (provider *OIDCProvider) refreshJWKS() (err error) {
+	resp, err := oidcHTTPClient.Get(provider.JwksURI)
+	if err != nil {
+		return fmt.Errorf("unable to fetch OIDC JWKS : %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to fetch OIDC JWKS : got HTTP status %d", resp.StatusCode)
+	}
+
+	jwks := &oidcJWKS{}
+	if err = json.NewDecoder(resp.Body).Decode(jwks); err != nil {
+		return fmt.Errorf("unable to parse OIDC JWKS : %s", err)
+	}
+
+	provider.jwks = jwks
+	provider.jwksFetch = time.Now()
+
+	return nil
+}
+
+// key returns the JWK matching kid, refreshing the cached JWKS once if
+// it isn't found (the provider may have rotated its signing keys).
+func (provider *OIDCProvider) key(kid string) (*oidcJWK, error) {
+	if key := provider.cachedKey(kid); key != nil {
+		return key, nil
+	}
+
+	if err := provider.refreshJWKS(); err != nil {
+		return nil, err
+	}
+
+	if key := provider.cachedKey(kid); key != nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown OIDC signing key %s", kid)
+}
+
+func (provider *OIDCProvider) cachedKey(kid string) *oidcJWK {
+	if provider.jwks == nil {
+		return nil
+	}
+	for i := range provider.jwks.Keys {
+		if provider.jwks.Keys[i].Kid == kid {
+			return &provider.jwks.Keys[i]
+		}
+	}
+	return nil
+}
+
+// ExchangeOIDCCode exchanges an authorization code for tokens at the
+// provider's token endpoint, completing the PKCE flow started by
+// OIDCLogin.
+func ExchangeOIDCCode(provider *OIDCProvider, config *Configuration, code string, verifier string) (tokens *OIDCTokenResponse, err error) {
+	values := url.Values{}
+	values.Set("grant_type", "authorization_code")
+	values.Set("code", code)
+	values.Set("redirect_uri", config.OIDCRedirectURL)
+	values.Set("client_id", config.OIDCClientID)
+	values.Set("client_secret", config.OIDCClientSecret)
+	values.Set("code_verifier", verifier)
+
+	resp, err := oidcHTTPClient.PostForm(provider.TokenEndpoint, values)
+	if err != nil {
+		return nil, fmt.Errorf("unable to exchange OIDC authorization code : %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to exchange OIDC authorization code : got HTTP status %d", resp.StatusCode)
+	}
+
+	tokens = &OIDCTokenResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(tokens); err != nil {
+		return nil, fmt.Errorf("unable to parse OIDC token response : %s", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("OIDC token response did not include an id_token")
+	}
+
+	return tokens, nil
+}
+
+// VerifyOIDCIDToken checks the ID token signature against the
+// provider's JWKS and validates its issuer, audience, expiry and
+// nonce, returning the decoded claims on success.
+func VerifyOIDCIDToken(provider *OIDCProvider, config *Configuration, idToken string, nonce string) (claims map[string]interface{}, err error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed OIDC id_token")
+	}
+
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode OIDC id_token header : %s", err)
+	}
+	if err = json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("unable to parse OIDC id_token header : %s", err)
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported OIDC id_token signing algorithm %s", header.Alg)
+	}
+
+	key, err := provider.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := key.rsaPublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("unable to build OIDC signing key : %s", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode OIDC id_token signature : %s", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err = rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("OIDC id_token signature verification failed : %s", err)
+	}
+
+	payloadJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode OIDC id_token claims : %s", err)
+	}
+	if err = json.Unmarshal(payloadJSON, &claims); err != nil {
+		return nil, fmt.Errorf("unable to parse OIDC id_token claims : %s", err)
+	}
+
+	if iss, _ := claims["iss"].(string); iss != provider.IssuerURL {
+		return nil, fmt.Errorf("OIDC id_token issuer mismatch")
+	}
+	if !oidcAudienceContains(claims["aud"], config.OIDCClientID) {
+		return nil, fmt.Errorf("OIDC id_token audience mismatch")
+	}
+	if exp, ok := claims["exp"].(float64); !ok || time.Unix(int64(exp), 0).Before(time.Now()) {
+		return nil, fmt.Errorf("OIDC id_token has expired")
+	}
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, fmt.Errorf("OIDC id_token nonce mismatch")
+	}
+
+	return claims, nil
+}
+
+// oidcAudienceContains reports whether the "aud" claim, which per spec
+// may be a single string or an array of strings, contains clientID.
+func oidcAudienceContains(aud interface{}, clientID string) bool {
+	switch value := aud.(type) {
+	case string:
+		return value == clientID
+	case []interface{}:
+		for _, entry := range value {
+			if s, ok := entry.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rsaPublicKey decodes the JWK's base64url modulus and exponent into a
+// usable *rsa.PublicKey.
+func (key *oidcJWK) rsaPublicKey() (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(key.N)
+	if err != nil {
+		return nil, err
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(key.E)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(new(big.Int).SetBytes(eBytes).Int64()),
+	}, nil
+}
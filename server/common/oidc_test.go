@@ -0,0 +1,152 @@
+package common
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func generateTestOIDCToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	header := map[string]interface{}{"alg": "RS256", "kid": kid, "typ": "JWT"}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf("unable to marshal test header : %s", err)
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("unable to marshal test claims : %s", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("unable to sign test token : %s", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// testOIDCProvider builds a provider with a pre-seeded JWKS cache, so
+// VerifyOIDCIDToken can be exercised without a live discovery endpoint.
+func testOIDCProvider(key *rsa.PrivateKey, kid string) (*OIDCProvider, *Configuration) {
+	jwk := oidcJWK{
+		Kid: kid,
+		Kty: "RSA",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+	}
+
+	provider := &OIDCProvider{
+		IssuerURL:     "https://issuer.example.com",
+		TokenEndpoint: "https://issuer.example.com/token",
+		jwks:          &oidcJWKS{Keys: []oidcJWK{jwk}},
+		jwksFetch:     time.Now(),
+	}
+
+	config := NewConfiguration()
+	config.OIDCClientID = "test-client"
+
+	return provider, config
+}
+
+func TestVerifyOIDCIDTokenSuccess(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate test key : %s", err)
+	}
+	provider, config := testOIDCProvider(key, "test-key")
+
+	claims := map[string]interface{}{
+		"iss":   provider.IssuerURL,
+		"aud":   config.OIDCClientID,
+		"exp":   float64(time.Now().Add(time.Hour).Unix()),
+		"nonce": "test-nonce",
+		"email": "user@example.com",
+	}
+	idToken := generateTestOIDCToken(t, key, "test-key", claims)
+
+	got, err := VerifyOIDCIDToken(provider, config, idToken, "test-nonce")
+	if err != nil {
+		t.Fatalf("unable to verify id_token : %s", err)
+	}
+	if got["email"] != "user@example.com" {
+		t.Fatalf("unexpected claims : %+v", got)
+	}
+}
+
+func TestVerifyOIDCIDTokenRejectsNonceMismatch(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	provider, config := testOIDCProvider(key, "test-key")
+
+	claims := map[string]interface{}{
+		"iss":   provider.IssuerURL,
+		"aud":   config.OIDCClientID,
+		"exp":   float64(time.Now().Add(time.Hour).Unix()),
+		"nonce": "actual-nonce",
+	}
+	idToken := generateTestOIDCToken(t, key, "test-key", claims)
+
+	if _, err := VerifyOIDCIDToken(provider, config, idToken, "expected-nonce"); err == nil {
+		t.Fatalf("expected nonce mismatch to be rejected")
+	}
+}
+
+func TestVerifyOIDCIDTokenRejectsExpired(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	provider, config := testOIDCProvider(key, "test-key")
+
+	claims := map[string]interface{}{
+		"iss":   provider.IssuerURL,
+		"aud":   config.OIDCClientID,
+		"exp":   float64(time.Now().Add(-time.Hour).Unix()),
+		"nonce": "test-nonce",
+	}
+	idToken := generateTestOIDCToken(t, key, "test-key", claims)
+
+	if _, err := VerifyOIDCIDToken(provider, config, idToken, "test-nonce"); err == nil {
+		t.Fatalf("expected expired id_token to be rejected")
+	}
+}
+
+func TestVerifyOIDCIDTokenRejectsAudienceMismatch(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	provider, config := testOIDCProvider(key, "test-key")
+
+	claims := map[string]interface{}{
+		"iss":   provider.IssuerURL,
+		"aud":   "someone-else",
+		"exp":   float64(time.Now().Add(time.Hour).Unix()),
+		"nonce": "test-nonce",
+	}
+	idToken := generateTestOIDCToken(t, key, "test-key", claims)
+
+	if _, err := VerifyOIDCIDToken(provider, config, idToken, "test-nonce"); err == nil {
+		t.Fatalf("expected audience mismatch to be rejected")
+	}
+}
+
+func TestVerifyOIDCIDTokenRejectsBadSignature(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	provider, config := testOIDCProvider(key, "test-key")
+
+	claims := map[string]interface{}{
+		"iss":   provider.IssuerURL,
+		"aud":   config.OIDCClientID,
+		"exp":   float64(time.Now().Add(time.Hour).Unix()),
+		"nonce": "test-nonce",
+	}
+	idToken := generateTestOIDCToken(t, otherKey, "test-key", claims)
+
+	if _, err := VerifyOIDCIDToken(provider, config, idToken, "test-nonce"); err == nil {
+		t.Fatalf("expected a token signed by an untrusted key to be rejected")
+	}
+}
@@ -0,0 +1,75 @@
+package common
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// Session is the server-side record a plik-session cookie resolves to.
+// It is created once at login, before the cookie is ever handed to the
+// client, so the cookie value authenticates a real user instead of
+// being an opaque token nothing can look up.
+type Session struct {
+	Username              string
+	Claims                map[string]interface{}
+	EncryptedRefreshToken string
+	Expiry                time.Time
+}
+
+// sessions holds every active Session in memory, keyed by the opaque
+// id issued in the plik-session cookie. It does not survive a
+// restart ; a missing id is simply treated as logged out.
+var sessions = struct {
+	mu   sync.RWMutex
+	byID map[string]*Session
+}{byID: make(map[string]*Session)}
+
+// CreateSession mints a new opaque session id bound to username and
+// claims, valid for ttl, and returns it for use as the session cookie
+// value.
+func CreateSession(username string, claims map[string]interface{}, ttl time.Duration) (id string, err error) {
+	buffer := make([]byte, 32)
+	if _, err = rand.Read(buffer); err != nil {
+		return "", err
+	}
+	id = base64.RawURLEncoding.EncodeToString(buffer)
+
+	sessions.mu.Lock()
+	sessions.byID[id] = &Session{Username: username, Claims: claims, Expiry: time.Now().Add(ttl)}
+	sessions.mu.Unlock()
+
+	return id, nil
+}
+
+// GetSession returns the session bound to id, or nil if it doesn't
+// exist or has expired.
+func GetSession(id string) *Session {
+	sessions.mu.RLock()
+	session, ok := sessions.byID[id]
+	sessions.mu.RUnlock()
+
+	if !ok || time.Now().After(session.Expiry) {
+		return nil
+	}
+	return session
+}
+
+// SetSessionRefreshToken attaches an already encrypted OAuth refresh
+// token to an existing session, so Logout can later revoke it
+// upstream. It is a no-op if the session no longer exists.
+func SetSessionRefreshToken(id string, encryptedRefreshToken string) {
+	sessions.mu.Lock()
+	if session, ok := sessions.byID[id]; ok {
+		session.EncryptedRefreshToken = encryptedRefreshToken
+	}
+	sessions.mu.Unlock()
+}
+
+// DeleteSession removes a session, e.g. on logout.
+func DeleteSession(id string) {
+	sessions.mu.Lock()
+	delete(sessions.byID, id)
+	sessions.mu.Unlock()
+}
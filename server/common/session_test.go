@@ -0,0 +1,38 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCreateSessionGetSession(t *testing.T) {
+	id, err := CreateSession("jdoe", map[string]interface{}{"email": "jdoe@example.com"}, time.Hour)
+	if err != nil {
+		t.Fatalf("unable to create session : %s", err)
+	}
+
+	session := GetSession(id)
+	if session == nil {
+		t.Fatalf("expected session %q to be found", id)
+	}
+	if session.Username != "jdoe" {
+		t.Fatalf("expected username jdoe, got %q", session.Username)
+	}
+}
+
+func TestGetSessionUnknown(t *testing.T) {
+	if GetSession("does-not-exist") != nil {
+		t.Fatalf("expected unknown session id to resolve to nil")
+	}
+}
+
+func TestGetSessionExpired(t *testing.T) {
+	id, err := CreateSession("jdoe", nil, -time.Second)
+	if err != nil {
+		t.Fatalf("unable to create session : %s", err)
+	}
+
+	if GetSession(id) != nil {
+		t.Fatalf("expected an already expired session to resolve to nil")
+	}
+}
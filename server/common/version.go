@@ -0,0 +1,5 @@
+package common
+
+// Version is the Plik server version. It is set at build time with
+// -ldflags "-X github.com/root-gg/plik/server/common.Version=...".
+var Version = "dev"
@@ -0,0 +1,245 @@
+// Package s3 implements Plik's data backend on top of any S3-compatible
+// object store, configured by the DataBackendConfig keys documented in
+// Backend. Uploads are streamed straight into a multipart upload so
+// Plik's streaming mode never buffers a full file in memory or on
+// local disk.
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"time"
+
+	minio "github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+
+	"github.com/root-gg/plik/server/common"
+	"github.com/root-gg/plik/server/metrics"
+)
+
+// backendName is the label used to attribute this backend's calls in
+// plik_backend_latency_seconds.
+const backendName = "s3"
+
+// minPartSize is the smallest multipart upload part size S3 accepts.
+const minPartSize = 5 * 1024 * 1024 // 5MB
+
+// Backend stores upload files in an S3-compatible bucket. It is
+// configured from Configuration.DataBackendConfig with the following
+// keys : Endpoint, Region, Bucket, AccessKey, SecretKey, UseSSL,
+// PathStyle, SSECustomerKey, PartSize, UserAgent.
+type Backend struct {
+	config *BackendConfig
+	client *minio.Client
+}
+
+// BackendConfig holds the Backend settings parsed out of
+// Configuration.DataBackendConfig.
+type BackendConfig struct {
+	Endpoint       string
+	Region         string
+	Bucket         string
+	AccessKey      string
+	SecretKey      string
+	UseSSL         bool
+	PathStyle      bool
+	SSECustomerKey string
+	PartSize       uint64
+	UserAgent      string
+}
+
+// NewBackendConfig builds a BackendConfig from the raw
+// DataBackendConfig map loaded from the TOML file.
+func NewBackendConfig(params map[string]interface{}) (config *BackendConfig) {
+	config = &BackendConfig{PartSize: minPartSize}
+
+	if value, ok := params["Endpoint"].(string); ok {
+		config.Endpoint = value
+	}
+	if value, ok := params["Region"].(string); ok {
+		config.Region = value
+	}
+	if value, ok := params["Bucket"].(string); ok {
+		config.Bucket = value
+	}
+	if value, ok := params["AccessKey"].(string); ok {
+		config.AccessKey = value
+	}
+	if value, ok := params["SecretKey"].(string); ok {
+		config.SecretKey = value
+	}
+	if value, ok := params["UseSSL"].(bool); ok {
+		config.UseSSL = value
+	}
+	if value, ok := params["PathStyle"].(bool); ok {
+		config.PathStyle = value
+	}
+	if value, ok := params["SSECustomerKey"].(string); ok {
+		config.SSECustomerKey = value
+	}
+	if value, ok := params["PartSize"].(int64); ok && value > 0 {
+		config.PartSize = uint64(value)
+	}
+	if value, ok := params["UserAgent"].(string); ok {
+		config.UserAgent = value
+	}
+
+	return config
+}
+
+// NewBackend creates an S3 Backend and tags every outbound request
+// with a User-Agent bucket owners can use to attribute traffic, in the
+// form "plik/<version> (<go-version>; <os>/<arch>) <UserAgent-override>".
+func NewBackend(config *BackendConfig) (backend *Backend, err error) {
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
+		Secure:       config.UseSSL,
+		Region:       config.Region,
+		BucketLookup: lookupStyle(config.PathStyle),
+		Transport:    &userAgentTransport{userAgent: userAgent(config.UserAgent), base: http.DefaultTransport},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create S3 client : %s", err)
+	}
+
+	return &Backend{config: config, client: client}, nil
+}
+
+// lookupStyle selects path-style bucket addressing for S3-compatible
+// stores (e.g. MinIO behind a single endpoint) that don't support
+// virtual-host-style buckets.
+func lookupStyle(pathStyle bool) minio.BucketLookupType {
+	if pathStyle {
+		return minio.BucketLookupPath
+	}
+	return minio.BucketLookupAuto
+}
+
+// userAgent builds "plik/<version> (<go-version>; <os>/<arch>) <override>",
+// the exact form bucket owners need to attribute traffic.
+func userAgent(override string) string {
+	agent := fmt.Sprintf("plik/%s (%s; %s/%s)", common.Version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	if override != "" {
+		agent += " " + override
+	}
+	return agent
+}
+
+// userAgentTransport forces the User-Agent header on every request,
+// since minio-go's own SetAppInfo appends to its own UA string rather
+// than letting the caller control the full value.
+type userAgentTransport struct {
+	userAgent string
+	base      http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.base.RoundTrip(req)
+}
+
+// AddFile streams reader into the bucket as a multipart upload keyed
+// by id, so large files never touch local disk or get buffered whole
+// in memory.
+func (backend *Backend) AddFile(id string, reader io.Reader) (err error) {
+	metrics.StreamsInFlight.Inc()
+	defer metrics.StreamsInFlight.Dec()
+
+	sse, err := backend.sse()
+	if err != nil {
+		return fmt.Errorf("unable to upload file %s to S3 : %s", id, err)
+	}
+
+	start := time.Now()
+	info, err := backend.client.PutObject(context.TODO(), backend.config.Bucket, backend.objectKey(id), reader, -1, minio.PutObjectOptions{
+		PartSize:             backend.config.PartSize,
+		ServerSideEncryption: sse,
+	})
+	metrics.ObserveBackendLatency(backendName, "put", start)
+	if err != nil {
+		return fmt.Errorf("unable to upload file %s to S3 : %s", id, err)
+	}
+
+	metrics.UploadsTotal.Inc()
+	metrics.UploadBytesTotal.Add(float64(info.Size))
+	return nil
+}
+
+// GetFile streams the object back out of the bucket. The returned
+// reader counts bytes into plik_download_bytes_total as it is read.
+func (backend *Backend) GetFile(id string) (reader io.ReadCloser, err error) {
+	sse, err := backend.sse()
+	if err != nil {
+		return nil, fmt.Errorf("unable to download file %s from S3 : %s", id, err)
+	}
+
+	options := minio.GetObjectOptions{}
+	if sse != nil {
+		sse.Marshal(options.Header())
+	}
+
+	start := time.Now()
+	object, err := backend.client.GetObject(context.TODO(), backend.config.Bucket, backend.objectKey(id), options)
+	metrics.ObserveBackendLatency(backendName, "get", start)
+	if err != nil {
+		return nil, fmt.Errorf("unable to download file %s from S3 : %s", id, err)
+	}
+
+	metrics.StreamsInFlight.Inc()
+	return &countingReadCloser{ReadCloser: object}, nil
+}
+
+// countingReadCloser tallies bytes read into plik_download_bytes_total
+// and decrements plik_streams_in_flight when the stream closes.
+type countingReadCloser struct {
+	io.ReadCloser
+}
+
+func (r *countingReadCloser) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	metrics.DownloadBytesTotal.Add(float64(n))
+	return n, err
+}
+
+func (r *countingReadCloser) Close() error {
+	metrics.StreamsInFlight.Dec()
+	return r.ReadCloser.Close()
+}
+
+// sse builds the SSE-C customer key options when SSECustomerKey is
+// configured, or nil otherwise. A malformed key is reported as an
+// error instead of being silently dropped, so a misconfigured
+// SSECustomerKey can never result in an object being stored or read
+// back without the encryption an operator asked for.
+func (backend *Backend) sse() (encrypt.ServerSide, error) {
+	if backend.config.SSECustomerKey == "" {
+		return nil, nil
+	}
+	sse, err := encrypt.NewSSEC([]byte(backend.config.SSECustomerKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSECustomerKey : %s", err)
+	}
+	return sse, nil
+}
+
+// RemoveFile deletes the object from the bucket.
+func (backend *Backend) RemoveFile(id string) (err error) {
+	start := time.Now()
+	err = backend.client.RemoveObject(context.TODO(), backend.config.Bucket, backend.objectKey(id), minio.RemoveObjectOptions{})
+	metrics.ObserveBackendLatency(backendName, "remove", start)
+	if err != nil {
+		return fmt.Errorf("unable to remove file %s from S3 : %s", id, err)
+	}
+	return nil
+}
+
+// objectKey maps a Plik file id to its S3 object key. The id is used
+// as the key unchanged ; PathStyle only affects bucket addressing.
+func (backend *Backend) objectKey(id string) string {
+	return id
+}
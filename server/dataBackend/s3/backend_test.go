@@ -0,0 +1,129 @@
+package s3
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"runtime"
+	"testing"
+
+	minio "github.com/minio/minio-go/v7"
+
+	"github.com/root-gg/plik/server/common"
+)
+
+func TestUserAgent(t *testing.T) {
+	expected := "plik/" + common.Version + " (" + runtime.Version() + "; " + runtime.GOOS + "/" + runtime.GOARCH + ")"
+	if got := userAgent(""); got != expected {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+
+	if got := userAgent("my-app/1.0"); got != expected+" my-app/1.0" {
+		t.Fatalf("expected override to be appended, got %q", got)
+	}
+}
+
+func TestLookupStyle(t *testing.T) {
+	if lookupStyle(true) != minio.BucketLookupPath {
+		t.Fatalf("expected path-style lookup when PathStyle is set")
+	}
+	if lookupStyle(false) != minio.BucketLookupAuto {
+		t.Fatalf("expected auto lookup when PathStyle is unset")
+	}
+}
+
+func TestNewBackendConfig(t *testing.T) {
+	config := NewBackendConfig(map[string]interface{}{
+		"Endpoint":  "s3.example.com",
+		"Bucket":    "plik",
+		"UseSSL":    true,
+		"PartSize":  int64(16 * 1024 * 1024),
+		"UserAgent": "attribution-tag",
+	})
+
+	if config.Endpoint != "s3.example.com" || config.Bucket != "plik" || !config.UseSSL {
+		t.Fatalf("unexpected config : %+v", config)
+	}
+	if config.PartSize != 16*1024*1024 {
+		t.Fatalf("expected PartSize override to be applied, got %d", config.PartSize)
+	}
+	if config.UserAgent != "attribution-tag" {
+		t.Fatalf("expected UserAgent to be parsed, got %q", config.UserAgent)
+	}
+}
+
+func TestNewBackendConfigDefaults(t *testing.T) {
+	config := NewBackendConfig(map[string]interface{}{})
+	if config.PartSize != minPartSize {
+		t.Fatalf("expected default PartSize %d, got %d", minPartSize, config.PartSize)
+	}
+}
+
+func TestSSENoCustomerKey(t *testing.T) {
+	backend := &Backend{config: &BackendConfig{}}
+	sse, err := backend.sse()
+	if err != nil {
+		t.Fatalf("unexpected error : %s", err)
+	}
+	if sse != nil {
+		t.Fatalf("expected no server-side encryption without a customer key")
+	}
+}
+
+func TestSSERejectsMalformedCustomerKey(t *testing.T) {
+	backend := &Backend{config: &BackendConfig{SSECustomerKey: "too-short"}}
+	if _, err := backend.sse(); err == nil {
+		t.Fatalf("expected a malformed SSECustomerKey to be reported instead of silently disabling encryption")
+	}
+}
+
+// TestIntegrationAddGetRemoveFile exercises the backend against a real
+// S3-compatible endpoint (e.g. MinIO). It is skipped unless
+// PLIK_TEST_S3_ENDPOINT is set, since it requires network access to a
+// running server : see the MinIO quickstart at
+// https://min.io/docs/minio/container/index.html for a local target.
+func TestIntegrationAddGetRemoveFile(t *testing.T) {
+	endpoint := os.Getenv("PLIK_TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("PLIK_TEST_S3_ENDPOINT not set, skipping S3 integration test")
+	}
+
+	config := NewBackendConfig(map[string]interface{}{
+		"Endpoint":  endpoint,
+		"Bucket":    os.Getenv("PLIK_TEST_S3_BUCKET"),
+		"AccessKey": os.Getenv("PLIK_TEST_S3_ACCESS_KEY"),
+		"SecretKey": os.Getenv("PLIK_TEST_S3_SECRET_KEY"),
+		"PathStyle": true,
+	})
+
+	backend, err := NewBackend(config)
+	if err != nil {
+		t.Fatalf("unable to create backend : %s", err)
+	}
+
+	id := "backend-test-" + common.Version
+	content := []byte("hello from the plik s3 backend integration test")
+
+	if err := backend.AddFile(id, bytes.NewReader(content)); err != nil {
+		t.Fatalf("unable to upload file : %s", err)
+	}
+	defer backend.RemoveFile(id)
+
+	reader, err := backend.GetFile(id)
+	if err != nil {
+		t.Fatalf("unable to download file : %s", err)
+	}
+	defer reader.Close()
+
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("unable to read downloaded file : %s", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatalf("downloaded content does not match uploaded content")
+	}
+
+	if err := backend.RemoveFile(id); err != nil {
+		t.Fatalf("unable to remove file : %s", err)
+	}
+}
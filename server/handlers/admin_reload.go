@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/root-gg/logger"
+	"github.com/root-gg/plik/server/common"
+	"github.com/root-gg/plik/server/metrics"
+)
+
+// AdminReload re-parses the on-disk configuration file and swaps the
+// mutable configuration fields in, for orchestrators that have no way
+// to deliver a SIGHUP to the Plik process. The caller must present
+// config.AdminAPIKey as a bearer token.
+func AdminReload(config *common.Configuration, configFilePath string, resp http.ResponseWriter, req *http.Request) {
+	if !isAuthorizedAdmin(config.Current(), req) {
+		http.Error(resp, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err := config.Reload(configFilePath)
+
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	metrics.ConfigReloadTotal.WithLabelValues(result).Inc()
+
+	if err != nil {
+		http.Error(resp, fmt.Sprintf("unable to reload configuration : %s", err), http.StatusBadRequest)
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	fmt.Fprintln(resp, "configuration reloaded")
+}
+
+// isAuthorizedAdmin checks the request's bearer token against
+// config.AdminAPIKey in constant time. The endpoint is disabled
+// entirely when no key is configured.
+func isAuthorizedAdmin(config *common.Configuration, req *http.Request) bool {
+	if config.AdminAPIKey == "" {
+		return false
+	}
+
+	token := req.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(token) <= len(prefix) || token[:len(prefix)] != prefix {
+		return false
+	}
+	token = token[len(prefix):]
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(config.AdminAPIKey)) == 1
+}
+
+// WatchReloadSignal binds SIGHUP to config.Reload, so administrators
+// who can reach the host but not the HTTP API can still trigger a
+// reload. It blocks and should be run in its own goroutine from the
+// server main.
+func WatchReloadSignal(config *common.Configuration, configFilePath string, log *logger.Logger) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	for range signals {
+		err := config.Reload(configFilePath)
+
+		result := "success"
+		if err != nil {
+			result = "error"
+		}
+		metrics.ConfigReloadTotal.WithLabelValues(result).Inc()
+
+		if err != nil {
+			log.Warningf("unable to reload configuration on SIGHUP : %s", err)
+			continue
+		}
+		log.Infof("configuration reloaded on SIGHUP")
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/root-gg/plik/server/common"
+)
+
+// logoutHTTPClient performs the best-effort revocation call. It still
+// needs a timeout : Logout deletes the session and clears the cookie
+// after this call returns, so a provider that never responds would
+// otherwise leave the user looking logged in for as long as the
+// request hangs.
+var logoutHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// Logout looks up the caller's session from the plik-session cookie,
+// best-effort revokes its stored OAuth refresh token upstream, deletes
+// the session and clears the cookie. Revocation failures never block
+// the logout itself : a user must always be able to log out locally.
+func Logout(config *common.Configuration, resp http.ResponseWriter, req *http.Request) {
+	config = config.Current()
+
+	if cookie, err := req.Cookie("plik-session"); err == nil {
+		if session := common.GetSession(cookie.Value); session != nil {
+			revokeSessionRefreshToken(config, session)
+		}
+		common.DeleteSession(cookie.Value)
+	}
+
+	http.SetCookie(resp, &http.Cookie{Name: "plik-session", Value: "", Path: "/", MaxAge: -1, HttpOnly: true, Secure: config.SslEnabled})
+	resp.WriteHeader(http.StatusOK)
+}
+
+// revokeSessionRefreshToken decrypts session's stored refresh token, if
+// any, and revokes it at the OIDC provider's revocation endpoint.
+func revokeSessionRefreshToken(config *common.Configuration, session *common.Session) {
+	if session.EncryptedRefreshToken == "" || config.SessionSecret == "" {
+		return
+	}
+
+	provider := config.GetOIDCProvider()
+	if provider == nil || provider.RevocationEndpoint == "" {
+		return
+	}
+
+	refreshToken, err := common.DecryptRefreshToken(config.SessionSecret, session.EncryptedRefreshToken)
+	if err != nil {
+		return
+	}
+
+	values := url.Values{}
+	values.Set("token", refreshToken)
+	values.Set("token_type_hint", "refresh_token")
+	values.Set("client_id", config.OIDCClientID)
+	values.Set("client_secret", config.OIDCClientSecret)
+
+	revokeResp, err := logoutHTTPClient.PostForm(provider.RevocationEndpoint, values)
+	if err != nil {
+		return
+	}
+	defer revokeResp.Body.Close()
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/root-gg/plik/server/common"
+	"github.com/root-gg/plik/server/metrics"
+)
+
+// OIDCLogin redirects the user to the configured OIDC provider's
+// authorization endpoint, starting an authorization-code flow with
+// PKCE. The generated state, nonce and code verifier are stored in
+// short lived cookies so OIDCCallback can complete the exchange.
+func OIDCLogin(config *common.Configuration, resp http.ResponseWriter, req *http.Request) {
+	config = config.Current()
+
+	provider := config.GetOIDCProvider()
+	if provider == nil {
+		http.Error(resp, "OIDC authentication is not enabled", http.StatusForbidden)
+		return
+	}
+
+	state, err := randomOIDCToken()
+	if err != nil {
+		http.Error(resp, fmt.Sprintf("unable to generate OIDC state : %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	verifier, err := randomOIDCToken()
+	if err != nil {
+		http.Error(resp, fmt.Sprintf("unable to generate OIDC code verifier : %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	nonce, err := randomOIDCToken()
+	if err != nil {
+		http.Error(resp, fmt.Sprintf("unable to generate OIDC nonce : %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(resp, &http.Cookie{Name: "plik-oidc-state", Value: state, Path: "/", HttpOnly: true, Secure: config.SslEnabled})
+	http.SetCookie(resp, &http.Cookie{Name: "plik-oidc-verifier", Value: verifier, Path: "/", HttpOnly: true, Secure: config.SslEnabled})
+	http.SetCookie(resp, &http.Cookie{Name: "plik-oidc-nonce", Value: nonce, Path: "/", HttpOnly: true, Secure: config.SslEnabled})
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", config.OIDCClientID)
+	query.Set("redirect_uri", config.OIDCRedirectURL)
+	query.Set("scope", joinOIDCScopes(config.OIDCScopes))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", oidcCodeChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+
+	http.Redirect(resp, req, provider.AuthorizationEndpoint+"?"+query.Encode(), http.StatusFound)
+}
+
+// OIDCCallback completes the authorization-code flow started by
+// OIDCLogin : it validates the state, exchanges the code for tokens,
+// verifies the ID token and creates the Plik user session.
+func OIDCCallback(config *common.Configuration, resp http.ResponseWriter, req *http.Request) {
+	config = config.Current()
+
+	result := "error"
+	defer func() { metrics.AuthAttemptsTotal.WithLabelValues("oidc", result).Inc() }()
+
+	provider := config.GetOIDCProvider()
+	if provider == nil {
+		http.Error(resp, "OIDC authentication is not enabled", http.StatusForbidden)
+		return
+	}
+
+	stateCookie, err := req.Cookie("plik-oidc-state")
+	if err != nil || req.URL.Query().Get("state") != stateCookie.Value {
+		http.Error(resp, "invalid OIDC state", http.StatusBadRequest)
+		return
+	}
+
+	verifierCookie, err := req.Cookie("plik-oidc-verifier")
+	if err != nil {
+		http.Error(resp, "missing OIDC code verifier", http.StatusBadRequest)
+		return
+	}
+
+	nonceCookie, err := req.Cookie("plik-oidc-nonce")
+	if err != nil {
+		http.Error(resp, "missing OIDC nonce", http.StatusBadRequest)
+		return
+	}
+
+	clearOIDCCookies(resp, config)
+
+	if errParam := req.URL.Query().Get("error"); errParam != "" {
+		http.Error(resp, fmt.Sprintf("OIDC authorization failed : %s", errParam), http.StatusBadRequest)
+		return
+	}
+
+	code := req.URL.Query().Get("code")
+	if code == "" {
+		http.Error(resp, "missing OIDC authorization code", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := common.ExchangeOIDCCode(provider, config, code, verifierCookie.Value)
+	if err != nil {
+		http.Error(resp, fmt.Sprintf("unable to complete OIDC login : %s", err), http.StatusBadGateway)
+		return
+	}
+
+	claims, err := common.VerifyOIDCIDToken(provider, config, tokens.IDToken, nonceCookie.Value)
+	if err != nil {
+		http.Error(resp, fmt.Sprintf("unable to complete OIDC login : %s", err), http.StatusUnauthorized)
+		return
+	}
+
+	username, _ := claims[config.OIDCUsernameClaim].(string)
+	if username == "" {
+		http.Error(resp, "OIDC id_token is missing the configured username claim", http.StatusUnauthorized)
+		return
+	}
+
+	if len(config.OIDCAllowedGroups) > 0 && !oidcGroupAllowed(claims[config.OIDCGroupsClaim], config.OIDCAllowedGroups) {
+		http.Error(resp, "user is not a member of an allowed OIDC group", http.StatusForbidden)
+		return
+	}
+
+	session, err := common.CreateSession(username, claims, time.Duration(config.SessionMaxLifetime)*time.Second)
+	if err != nil {
+		http.Error(resp, fmt.Sprintf("unable to create session : %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	if tokens.RefreshToken != "" && config.SessionSecret != "" {
+		if encrypted, err := common.EncryptRefreshToken(config.SessionSecret, tokens.RefreshToken); err == nil {
+			common.SetSessionRefreshToken(session, encrypted)
+		}
+	}
+
+	result = "success"
+
+	http.SetCookie(resp, &http.Cookie{
+		Name:     "plik-session",
+		Value:    session,
+		Path:     "/",
+		MaxAge:   config.SessionMaxLifetime,
+		HttpOnly: true,
+		Secure:   config.SslEnabled,
+	})
+
+	resp.WriteHeader(http.StatusOK)
+	fmt.Fprintln(resp, username)
+}
+
+// clearOIDCCookies removes the short lived cookies used to carry state
+// across the redirect to the OIDC provider, whether or not the
+// callback eventually succeeds.
+func clearOIDCCookies(resp http.ResponseWriter, config *common.Configuration) {
+	for _, name := range []string{"plik-oidc-state", "plik-oidc-verifier", "plik-oidc-nonce"} {
+		http.SetCookie(resp, &http.Cookie{Name: name, Value: "", Path: "/", MaxAge: -1, HttpOnly: true, Secure: config.SslEnabled})
+	}
+}
+
+// oidcGroupAllowed reports whether the groups claim, which may be a
+// single string or an array of strings, intersects with allowed.
+func oidcGroupAllowed(groupsClaim interface{}, allowed []string) bool {
+	var groups []string
+	switch value := groupsClaim.(type) {
+	case string:
+		groups = []string{value}
+	case []interface{}:
+		for _, entry := range value {
+			if s, ok := entry.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+	}
+
+	for _, group := range groups {
+		for _, candidate := range allowed {
+			if group == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func randomOIDCToken() (token string, err error) {
+	buffer := make([]byte, 32)
+	if _, err = rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buffer), nil
+}
+
+func oidcCodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func joinOIDCScopes(scopes []string) string {
+	joined := ""
+	for i, scope := range scopes {
+		if i > 0 {
+			joined += " "
+		}
+		joined += scope
+	}
+	return joined
+}
@@ -0,0 +1,155 @@
+// Package logging builds structured per-request log records and ships
+// them either to stdout (text or JSON) or to a remote collector via a
+// disk-buffered batching shipper.
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/root-gg/plik/server/common"
+)
+
+// Record is a single structured request log entry. Fields are
+// exported so the JSON encoder can marshal them ; Logger.filter trims
+// them down to Configuration.LogFields.
+type Record struct {
+	RequestID   string        `json:"request_id,omitempty"`
+	UserID      string        `json:"user_id,omitempty"`
+	TokenID     string        `json:"token_id,omitempty"`
+	SourceIP    string        `json:"source_ip,omitempty"`
+	UploadID    string        `json:"upload_id,omitempty"`
+	FileID      string        `json:"file_id,omitempty"`
+	Method      string        `json:"method,omitempty"`
+	Path        string        `json:"path,omitempty"`
+	Status      int           `json:"status,omitempty"`
+	BytesIn     int64         `json:"bytes_in,omitempty"`
+	BytesOut    int64         `json:"bytes_out,omitempty"`
+	Latency     time.Duration `json:"latency,omitempty"`
+	Backend     string        `json:"backend,omitempty"`
+	BackendTime time.Duration `json:"backend_time,omitempty"`
+	ErrorClass  string        `json:"error_class,omitempty"`
+	Timestamp   time.Time     `json:"timestamp"`
+}
+
+// Logger formats and emits Records according to the configured
+// LogFormat/LogFields/LogSampling, and forwards a copy to the Shipper
+// when RemoteLogURL is set.
+type Logger struct {
+	config  *common.Configuration
+	fields  map[string]bool
+	shipper *Shipper
+}
+
+// NewLogger builds a request Logger from the given configuration. When
+// RemoteLogURL is set, a Shipper is started to batch and ship records
+// in the background.
+func NewLogger(config *common.Configuration) (*Logger, error) {
+	fields := make(map[string]bool, len(config.LogFields))
+	for _, field := range config.LogFields {
+		fields[field] = true
+	}
+
+	log := &Logger{config: config, fields: fields}
+
+	if config.RemoteLogURL != "" {
+		shipper, err := NewShipper(config)
+		if err != nil {
+			return nil, fmt.Errorf("unable to start remote log shipper : %s", err)
+		}
+		log.shipper = shipper
+	}
+
+	return log, nil
+}
+
+// Log filters a Record down to the configured LogFields, applies
+// LogSampling, writes it to stdout in LogFormat, and hands it to the
+// remote shipper if configured.
+func (log *Logger) Log(record *Record) {
+	if log.config.LogSampling < 1 && !sample(log.config.LogSampling) {
+		return
+	}
+
+	record.Timestamp = time.Now()
+	filtered := log.filter(record)
+
+	switch log.config.LogFormat {
+	case "json":
+		data, err := json.Marshal(filtered)
+		if err == nil {
+			fmt.Println(string(data))
+		}
+	default:
+		fmt.Printf("%s %s %s %d %s\n", filtered.Timestamp.Format(time.RFC3339), filtered.Method, filtered.Path, filtered.Status, filtered.Latency)
+	}
+
+	if log.shipper != nil {
+		log.shipper.Ship(filtered)
+	}
+}
+
+// filter returns a copy of the record with only the fields present in
+// Configuration.LogFields populated, so operators can trim PII such as
+// SourceIP or UserID. An empty LogFields disables filtering : the
+// record itself is returned with every field.
+func (log *Logger) filter(record *Record) *Record {
+	if len(log.fields) == 0 {
+		return record
+	}
+
+	filtered := &Record{Timestamp: record.Timestamp}
+	if log.fields["request_id"] {
+		filtered.RequestID = record.RequestID
+	}
+	if log.fields["user_id"] {
+		filtered.UserID = record.UserID
+	}
+	if log.fields["token_id"] {
+		filtered.TokenID = record.TokenID
+	}
+	if log.fields["source_ip"] {
+		filtered.SourceIP = record.SourceIP
+	}
+	if log.fields["upload_id"] {
+		filtered.UploadID = record.UploadID
+	}
+	if log.fields["file_id"] {
+		filtered.FileID = record.FileID
+	}
+	if log.fields["method"] {
+		filtered.Method = record.Method
+	}
+	if log.fields["path"] {
+		filtered.Path = record.Path
+	}
+	if log.fields["status"] {
+		filtered.Status = record.Status
+	}
+	if log.fields["bytes_in"] {
+		filtered.BytesIn = record.BytesIn
+	}
+	if log.fields["bytes_out"] {
+		filtered.BytesOut = record.BytesOut
+	}
+	if log.fields["latency"] {
+		filtered.Latency = record.Latency
+	}
+	if log.fields["backend"] {
+		filtered.Backend = record.Backend
+	}
+	if log.fields["backend_time"] {
+		filtered.BackendTime = record.BackendTime
+	}
+	if log.fields["error_class"] {
+		filtered.ErrorClass = record.ErrorClass
+	}
+
+	return filtered
+}
+
+func sample(rate float64) bool {
+	return rand.Float64() < rate
+}
@@ -0,0 +1,263 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/root-gg/plik/server/common"
+)
+
+// shipperBatchSize is the number of records buffered in memory before
+// they are flushed to the remote endpoint.
+const shipperBatchSize = 100
+
+// shipperFlushInterval bounds how long a partial batch can sit before
+// being flushed.
+const shipperFlushInterval = 5 * time.Second
+
+// shipperMaxBufferBytes is the maximum size the on-disk buffer file is
+// allowed to grow to before the oldest records are dropped.
+const shipperMaxBufferBytes = 100 * 1024 * 1024 // 100MB
+
+// shipperHTTPClient ships each batch to RemoteLogURL. flush() no
+// longer holds shipper.mu while this runs, but flushLoop's ticker
+// still drives it from a single goroutine, so a collector that never
+// responds would otherwise stop every future flush from ever being
+// attempted.
+var shipperHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// Shipper batches Records, gzip-compresses them, and POSTs them to
+// RemoteLogURL. Records that fail to ship are appended to an on-disk
+// buffer file and replayed when the next Shipper starts (NewShipper),
+// so a crash or network outage does not lose the audit trail. A
+// running Shipper does not re-send buffered records.
+type Shipper struct {
+	url        string
+	token      string
+	bufferPath string
+
+	mu      sync.Mutex
+	batch   []*Record
+	lastErr error
+
+	// bufMu guards bufferPath, separately from mu, so a concurrent
+	// flush()'s disk I/O can never block Ship() callers batching into
+	// memory.
+	bufMu sync.Mutex
+}
+
+// NewShipper creates a Shipper and replays any records left over in
+// RemoteLogBufferPath from a previous run.
+func NewShipper(config *common.Configuration) (*Shipper, error) {
+	shipper := &Shipper{
+		url:        config.RemoteLogURL,
+		token:      config.RemoteLogToken,
+		bufferPath: config.RemoteLogBufferPath,
+	}
+
+	if shipper.bufferPath != "" {
+		if err := shipper.replayBuffer(); err != nil {
+			return nil, err
+		}
+	}
+
+	go shipper.flushLoop()
+
+	return shipper, nil
+}
+
+// Ship queues a record for the next batch flush.
+func (shipper *Shipper) Ship(record *Record) {
+	shipper.mu.Lock()
+	shipper.batch = append(shipper.batch, record)
+	full := len(shipper.batch) >= shipperBatchSize
+	shipper.mu.Unlock()
+
+	if full {
+		shipper.flush()
+	}
+}
+
+func (shipper *Shipper) flushLoop() {
+	ticker := time.NewTicker(shipperFlushInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		shipper.flush()
+	}
+}
+
+// flush sends the current batch upstream without holding shipper.mu,
+// so a slow or unreachable RemoteLogURL cannot block concurrent
+// Ship() callers. On failure the batch is appended to the on-disk
+// buffer instead of being dropped.
+func (shipper *Shipper) flush() {
+	shipper.mu.Lock()
+	batch := shipper.batch
+	shipper.batch = nil
+	shipper.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := shipper.send(batch); err != nil {
+		shipper.mu.Lock()
+		shipper.lastErr = err
+		shipper.mu.Unlock()
+		shipper.appendBuffer(batch)
+	}
+}
+
+func (shipper *Shipper) send(batch []*Record) error {
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	encoder := json.NewEncoder(gzipWriter)
+	for _, record := range batch {
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("unable to encode log batch : %s", err)
+		}
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("unable to compress log batch : %s", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, shipper.url, &buffer)
+	if err != nil {
+		return fmt.Errorf("unable to build log shipping request : %s", err)
+	}
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if shipper.token != "" {
+		req.Header.Set("Authorization", "Bearer "+shipper.token)
+	}
+
+	resp, err := shipperHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to ship log batch : %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unable to ship log batch : got HTTP status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// appendBuffer persists a failed batch to disk, dropping the oldest
+// buffered records first if the file has grown past
+// shipperMaxBufferBytes. flush() may run concurrently from flushLoop's
+// ticker and from Ship() hitting shipperBatchSize on another
+// goroutine, so bufMu serializes every access to bufferPath.
+func (shipper *Shipper) appendBuffer(batch []*Record) {
+	if shipper.bufferPath == "" {
+		return
+	}
+
+	shipper.bufMu.Lock()
+	defer shipper.bufMu.Unlock()
+
+	file, err := os.OpenFile(shipper.bufferPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, record := range batch {
+		_ = encoder.Encode(record)
+	}
+
+	shipper.trimBufferLocked()
+}
+
+// trimBufferLocked drops the oldest lines of the buffer file once it
+// exceeds shipperMaxBufferBytes, so a prolonged outage cannot fill the
+// disk. Callers must hold bufMu.
+func (shipper *Shipper) trimBufferLocked() {
+	info, err := os.Stat(shipper.bufferPath)
+	if err != nil || info.Size() <= shipperMaxBufferBytes {
+		return
+	}
+
+	file, err := os.Open(shipper.bufferPath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	drop := len(lines) / 2
+	if drop == 0 {
+		return
+	}
+
+	tmpPath := shipper.bufferPath + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return
+	}
+	for _, line := range lines[drop:] {
+		fmt.Fprintln(tmp, line)
+	}
+	tmp.Close()
+
+	os.Rename(tmpPath, shipper.bufferPath)
+}
+
+// replayBuffer re-ships any records left in the on-disk buffer from a
+// previous crash, clearing the file once they have been sent. bufMu is
+// held only around the file access, not across the network send, so
+// it never blocks appendBuffer for longer than a disk read.
+func (shipper *Shipper) replayBuffer() error {
+	shipper.bufMu.Lock()
+	file, err := os.Open(shipper.bufferPath)
+	if os.IsNotExist(err) {
+		shipper.bufMu.Unlock()
+		return nil
+	}
+	if err != nil {
+		shipper.bufMu.Unlock()
+		return fmt.Errorf("unable to open log buffer file : %s", err)
+	}
+
+	var batch []*Record
+	decoder := json.NewDecoder(file)
+	for decoder.More() {
+		record := &Record{}
+		if err := decoder.Decode(record); err != nil {
+			break
+		}
+		batch = append(batch, record)
+	}
+	file.Close()
+	shipper.bufMu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if err := shipper.send(batch); err != nil {
+		// Leave the buffer file in place ; it will be retried the
+		// next time a Shipper starts.
+		return nil
+	}
+
+	shipper.bufMu.Lock()
+	defer shipper.bufMu.Unlock()
+	return os.Remove(shipper.bufferPath)
+}
@@ -0,0 +1,166 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/root-gg/plik/server/common"
+)
+
+// healthHTTPClient probes the Google/OVH/OIDC discovery endpoints.
+// ServeReady runs every check synchronously on each request, so a
+// provider outage must fail fast rather than leaving /health/ready
+// hanging and tripping an orchestrator's own probe timeout instead of
+// Plik's.
+var healthHTTPClient = &http.Client{Timeout: 5 * time.Second}
+
+// googleDiscoveryURL is Google's well-known OpenID discovery document,
+// probed as a readiness check when GoogleAuthentication is enabled.
+const googleDiscoveryURL = "https://accounts.google.com/.well-known/openid-configuration"
+
+// Check is a single health probe, e.g. "data backend reachable" or
+// "disk space above threshold". It should return quickly and never
+// panic.
+type Check struct {
+	Name string
+	Fn   func() error
+}
+
+// CheckRegistry tracks the set of readiness checks and whether the
+// server has finished its startup sequence. /health/live always
+// succeeds once the process is up ; /health/ready only succeeds once
+// Initialize() has completed and every check passes.
+type CheckRegistry struct {
+	mu     sync.RWMutex
+	checks []Check
+	ready  bool
+}
+
+// NewCheckRegistry creates an empty registry. Readiness checks are
+// added with Register and the registry is flipped ready once startup
+// completes.
+func NewCheckRegistry() *CheckRegistry {
+	return &CheckRegistry{}
+}
+
+// Register adds a readiness check. It must be called before the
+// registry is marked ready.
+func (registry *CheckRegistry) Register(check Check) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.checks = append(registry.checks, check)
+}
+
+// SetReady flips the registry to ready once Initialize() has pinged
+// every backend successfully.
+func (registry *CheckRegistry) SetReady(ready bool) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.ready = ready
+}
+
+// ServeLive answers /health/live : it succeeds as soon as the process
+// can handle HTTP requests.
+func (registry *CheckRegistry) ServeLive(resp http.ResponseWriter, req *http.Request) {
+	resp.WriteHeader(http.StatusOK)
+	fmt.Fprintln(resp, "ok")
+}
+
+// ServeReady answers /health/ready : it runs every registered check
+// and fails until startup has completed and all of them pass.
+func (registry *CheckRegistry) ServeReady(resp http.ResponseWriter, req *http.Request) {
+	registry.mu.RLock()
+	ready := registry.ready
+	checks := registry.checks
+	registry.mu.RUnlock()
+
+	if !ready {
+		http.Error(resp, "not ready : startup in progress", http.StatusServiceUnavailable)
+		return
+	}
+
+	for _, check := range checks {
+		if err := check.Fn(); err != nil {
+			http.Error(resp, fmt.Sprintf("not ready : %s : %s", check.Name, err), http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	fmt.Fprintln(resp, "ok")
+}
+
+// NewDefaultRegistry registers the standard Plik readiness checks
+// (data backend, metadata backend, disk space, and the Google/OVH/OIDC
+// discovery endpoints when enabled), runs them once immediately, and
+// flips the registry ready only if every check passes - so
+// /health/ready cannot report healthy before Initialize() has
+// actually reached every backend.
+func NewDefaultRegistry(config *common.Configuration, diskPath string, pingDataBackend func() error, pingMetadataBackend func() error) (*CheckRegistry, error) {
+	config = config.Current()
+
+	registry := NewCheckRegistry()
+
+	registry.Register(Check{Name: "data backend reachable", Fn: pingDataBackend})
+	registry.Register(Check{Name: "metadata backend reachable", Fn: pingMetadataBackend})
+	registry.Register(Check{Name: "disk space", Fn: func() error {
+		return checkDiskSpace(diskPath, config.HealthMinFreeBytes)
+	}})
+
+	if config.GoogleAuthentication {
+		registry.Register(Check{Name: "Google discovery reachable", Fn: func() error {
+			return pingURL(googleDiscoveryURL)
+		}})
+	}
+	if config.OvhAuthentication {
+		endpoint := config.OvhAPIEndpoint
+		registry.Register(Check{Name: "OVH API reachable", Fn: func() error {
+			return pingURL(endpoint)
+		}})
+	}
+	if config.OIDCAuthentication {
+		provider := config.GetOIDCProvider()
+		registry.Register(Check{Name: "OIDC discovery reachable", Fn: func() error {
+			if provider == nil {
+				return fmt.Errorf("OIDC provider is not initialized")
+			}
+			return pingURL(provider.IssuerURL + "/.well-known/openid-configuration")
+		}})
+	}
+
+	for _, check := range registry.checks {
+		if err := check.Fn(); err != nil {
+			return registry, fmt.Errorf("readiness check %q failed : %s", check.Name, err)
+		}
+	}
+
+	registry.SetReady(true)
+	return registry, nil
+}
+
+// checkDiskSpace fails once free space on the filesystem holding path
+// drops below minFreeBytes.
+func checkDiskSpace(path string, minFreeBytes uint64) error {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return fmt.Errorf("unable to stat %s : %s", path, err)
+	}
+
+	free := uint64(stat.Bavail) * uint64(stat.Bsize)
+	if free < minFreeBytes {
+		return fmt.Errorf("only %d bytes free, need at least %d", free, minFreeBytes)
+	}
+	return nil
+}
+
+func pingURL(url string) error {
+	resp, err := healthHTTPClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/root-gg/plik/server/common"
+)
+
+func TestCheckRegistryReadyLifecycle(t *testing.T) {
+	registry := NewCheckRegistry()
+	registry.Register(Check{Name: "always ok", Fn: func() error { return nil }})
+
+	resp := httptest.NewRecorder()
+	registry.ServeReady(resp, httptest.NewRequest("GET", "/health/ready", nil))
+	if resp.Code != 503 {
+		t.Fatalf("expected 503 before SetReady, got %d", resp.Code)
+	}
+
+	registry.SetReady(true)
+	resp = httptest.NewRecorder()
+	registry.ServeReady(resp, httptest.NewRequest("GET", "/health/ready", nil))
+	if resp.Code != 200 {
+		t.Fatalf("expected 200 once ready, got %d", resp.Code)
+	}
+}
+
+func TestCheckRegistryReadyFailsOnFailingCheck(t *testing.T) {
+	registry := NewCheckRegistry()
+	registry.Register(Check{Name: "broken", Fn: func() error { return fmt.Errorf("boom") }})
+	registry.SetReady(true)
+
+	resp := httptest.NewRecorder()
+	registry.ServeReady(resp, httptest.NewRequest("GET", "/health/ready", nil))
+	if resp.Code != 503 {
+		t.Fatalf("expected a failing check to report 503, got %d", resp.Code)
+	}
+}
+
+func TestNewDefaultRegistryRegistersGoogleCheck(t *testing.T) {
+	config := common.NewConfiguration()
+	config.GoogleAuthentication = true
+
+	registry, _ := NewDefaultRegistry(config, t.TempDir(), func() error { return nil }, func() error { return nil })
+
+	found := false
+	for _, check := range registry.checks {
+		if check.Name == "Google discovery reachable" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected NewDefaultRegistry to register a Google discovery check when GoogleAuthentication is enabled")
+	}
+}
+
+func TestNewDefaultRegistrySkipsGoogleCheckWhenDisabled(t *testing.T) {
+	config := common.NewConfiguration()
+
+	registry, err := NewDefaultRegistry(config, t.TempDir(), func() error { return nil }, func() error { return nil })
+	if err != nil {
+		t.Fatalf("unable to build default registry : %s", err)
+	}
+
+	for _, check := range registry.checks {
+		if check.Name == "Google discovery reachable" {
+			t.Fatalf("expected no Google discovery check when GoogleAuthentication is disabled")
+		}
+	}
+}
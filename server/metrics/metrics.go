@@ -0,0 +1,96 @@
+// Package metrics registers the Prometheus collectors Plik exposes
+// for uploads, downloads, backend latency and authentication, and
+// wires them onto a dedicated HTTP server alongside the health-check
+// endpoints.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/root-gg/plik/server/common"
+)
+
+var (
+	// UploadsTotal counts completed uploads.
+	UploadsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "plik_uploads_total",
+		Help: "Total number of completed uploads.",
+	})
+
+	// UploadBytesTotal counts bytes received across all uploads.
+	UploadBytesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "plik_upload_bytes_total",
+		Help: "Total number of bytes received for uploads.",
+	})
+
+	// DownloadBytesTotal counts bytes served across all downloads.
+	DownloadBytesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "plik_download_bytes_total",
+		Help: "Total number of bytes served for downloads.",
+	})
+
+	// BackendLatency observes per-backend, per-operation latency for
+	// both the data and metadata backends.
+	BackendLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "plik_backend_latency_seconds",
+		Help: "Backend call latency in seconds.",
+	}, []string{"backend", "operation"})
+
+	// StreamsInFlight tracks the number of streaming uploads/downloads
+	// currently being served.
+	StreamsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "plik_streams_in_flight",
+		Help: "Number of streaming uploads/downloads currently in flight.",
+	})
+
+	// AuthAttemptsTotal counts authentication attempts by provider and
+	// outcome.
+	AuthAttemptsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "plik_authentication_attempts_total",
+		Help: "Total number of authentication attempts.",
+	}, []string{"provider", "result"})
+
+	// CleanerRunsTotal counts runs of the upload cleaning goroutine.
+	CleanerRunsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "plik_cleaner_runs_total",
+		Help: "Total number of upload cleaner goroutine runs.",
+	})
+
+	// ConfigReloadTotal counts configuration reload attempts by result
+	// ("success" or "error"), whether triggered by SIGHUP or the
+	// /admin/reload endpoint.
+	ConfigReloadTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "plik_config_reload_total",
+		Help: "Total number of configuration reload attempts.",
+	}, []string{"result"})
+)
+
+// ObserveBackendLatency is a small helper for the data/metadata backend
+// implementations to time an operation.
+func ObserveBackendLatency(backend string, operation string, start time.Time) {
+	BackendLatency.WithLabelValues(backend, operation).Observe(time.Since(start).Seconds())
+}
+
+// Serve starts the dedicated metrics/health HTTP server configured by
+// MetricsListenAddress. It never returns unless the server fails to
+// start or is shut down.
+func Serve(config *common.Configuration, registry *CheckRegistry) error {
+	config = config.Current()
+
+	mux := http.NewServeMux()
+	mux.Handle(config.MetricsPath, promhttp.Handler())
+	mux.HandleFunc(config.HealthPath+"/live", registry.ServeLive)
+	mux.HandleFunc(config.HealthPath+"/ready", registry.ServeReady)
+
+	server := &http.Server{
+		Addr:    config.MetricsListenAddress,
+		Handler: mux,
+	}
+
+	return server.ListenAndServe()
+}